@@ -0,0 +1,180 @@
+// Package mailstrip strips quoted text, signatures, and forwarded/reply
+// boilerplate from plain-text email bodies, leaving the fragments that make
+// up the actual message. It is a Go port of the algorithm used by GitHub's
+// email_reply_parser.
+package mailstrip
+
+import "strings"
+
+// Fragment is a chunk of an email body. An Email is made up of one or more
+// Fragments, in the order they appeared in the original message.
+type Fragment struct {
+	quoted    bool
+	signature bool
+	hidden    bool
+	forwarded bool
+
+	lines       []string
+	html        string
+	quoteHeader *QuoteHeader
+}
+
+// Quoted reports whether this fragment is part of a quoted reply (e.g. a
+// line starting with "> ", or the body of an "On ... wrote:" block).
+func (f *Fragment) Quoted() bool { return f.quoted }
+
+// Signature reports whether this fragment is a signature block (e.g. "--"
+// followed by a name, or "Sent from my iPhone").
+func (f *Fragment) Signature() bool { return f.signature }
+
+// Forwarded reports whether this fragment is part of a forwarded message.
+func (f *Fragment) Forwarded() bool { return f.forwarded }
+
+// Hidden reports whether this fragment should be hidden from users by
+// default: quoted replies and signatures are Hidden, the visible parts of
+// the message (including forwarded content) are not.
+func (f *Fragment) Hidden() bool { return f.hidden }
+
+// String returns the fragment's original text.
+func (f *Fragment) String() string {
+	return strings.Join(f.lines, "\n")
+}
+
+// HTML returns the fragment's original markup. It is only populated for
+// Fragments produced by ParseHTML; Fragments produced by Parse return the
+// same text as String.
+func (f *Fragment) HTML() string {
+	if f.html != "" {
+		return f.html
+	}
+	return f.String()
+}
+
+// Email is the result of parsing a message body: an ordered list of
+// Fragments.
+type Email []*Fragment
+
+// String returns the visible (non-Hidden) fragments of the email, joined
+// back together and trimmed of surrounding whitespace.
+func (e Email) String() string {
+	var visible []string
+	for _, f := range e {
+		if !f.Hidden() {
+			visible = append(visible, f.String())
+		}
+	}
+	return strings.TrimSpace(strings.Join(visible, "\n\n"))
+}
+
+// block is a maximal run of consecutive non-blank lines, as found by
+// splitting the body on blank lines.
+type block struct {
+	lines []string
+	// leadingBlanks is the number of blank lines between this block and
+	// the previous one (for the first block, the number of blank lines
+	// at the start of the body).
+	leadingBlanks int
+}
+
+func splitBlocks(lines []string) []block {
+	var (
+		blocks  []block
+		current block
+		blanks  int
+		started bool
+	)
+
+	flush := func() {
+		if started {
+			current.leadingBlanks = blanks
+			blocks = append(blocks, current)
+			current = block{}
+			started = false
+			blanks = 0
+		}
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			blanks++
+			continue
+		}
+		started = true
+		current.lines = append(current.lines, line)
+	}
+	flush()
+
+	return blocks
+}
+
+// Parse splits a plain-text email body into Fragments.
+func Parse(body string) Email {
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	lines := strings.Split(body, "\n")
+
+	blocks := splitBlocks(lines)
+
+	var (
+		email  Email
+		hidden bool
+		cur    *Fragment
+	)
+
+	flush := func() {
+		if cur != nil {
+			email = append(email, cur)
+			cur = nil
+		}
+	}
+
+	// flushWithGap closes the current plain fragment, first appending the
+	// blank lines that separated it from the block that's about to start a
+	// new fragment, so the blank line reads as part of the preceding
+	// fragment rather than disappearing.
+	flushWithGap := func(blanks int) {
+		if cur != nil {
+			appendGap(cur, blanks)
+		}
+		flush()
+	}
+
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+
+		kind, consumed, ok := match(b.lines)
+		if !ok {
+			h := hidden
+			if cur != nil && !cur.quoted && !cur.signature && !cur.forwarded && !h {
+				appendGap(cur, b.leadingBlanks)
+				cur.lines = append(cur.lines, b.lines...)
+				continue
+			}
+			flush()
+			cur = &Fragment{hidden: h}
+			cur.lines = append(cur.lines, b.lines...)
+			continue
+		}
+
+		if kind == FragmentKindQuoteHeader {
+			// The blank line before a quote header reads as the separator
+			// between it and the preceding fragment, not as trailing
+			// whitespace that belongs to that fragment, so it's dropped
+			// rather than appended (unlike the other cases below).
+			flush()
+		} else {
+			flushWithGap(b.leadingBlanks)
+		}
+
+		src := &sliceBlockSource{blocks: blocks, i: i + 1}
+		f, nextHidden, done := buildFragment(kind, b, consumed, hidden, src)
+		email = append(email, f)
+		hidden = nextHidden
+		if done {
+			break
+		}
+	}
+	flush()
+
+	return email
+}
@@ -0,0 +1,190 @@
+package mailstrip
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FragmentKind identifies what a Detector has recognised at the start of a
+// block of lines.
+type FragmentKind int
+
+const (
+	// FragmentKindQuoteHeader is an "On <date>, <person> wrote:"-style
+	// line, or block of lines when the header wraps. It's visible on its
+	// own, but any text already hidden (because it comes after a
+	// previously recognised quote/signature) stays hidden.
+	FragmentKindQuoteHeader FragmentKind = iota
+	// FragmentKindQuoteBlock is a marker, such as Yahoo's
+	// "-----Original Message-----" separator or Outlook's "From:" reply
+	// header, after which the rest of the message is quoted and hidden.
+	FragmentKindQuoteBlock
+	// FragmentKindForwarded introduces a forwarded message; the rest of
+	// the message stays visible.
+	FragmentKindForwarded
+	// FragmentKindQuoted is a quoted line or block, e.g. "> ...".
+	FragmentKindQuoted
+	// FragmentKindSignature is a signature block, e.g. "-- " or "Sent
+	// from my iPhone".
+	FragmentKindSignature
+)
+
+// Detector recognises a quote header, quote/forward marker, or signature at
+// the start of a block of lines (a block being the maximal run of
+// consecutive non-blank lines Parse is currently looking at). lines is
+// never empty. Match returns the FragmentKind it recognised and consumed,
+// the number of leading lines (>= 1) that make up the marker itself; a
+// consumed of 0 means lines didn't match. Detectors that only ever
+// recognise a single line, such as a locale's quote header or signature
+// marker, can ignore anything in lines beyond the first.
+type Detector interface {
+	Match(lines []string) (kind FragmentKind, consumed int)
+}
+
+// DetectorFunc adapts an ordinary function to a Detector.
+type DetectorFunc func(lines []string) (FragmentKind, int)
+
+// Match calls f.
+func (f DetectorFunc) Match(lines []string) (FragmentKind, int) { return f(lines) }
+
+// registry is the ordered set of Detectors Parse consults for each block,
+// in order, using the first match. The built-in detectors are registered
+// by init below; RegisterDetector appends to the end, so custom detectors
+// only take effect when none of the built-ins (or earlier custom
+// detectors) already matched.
+var registry []Detector
+
+// RegisterDetector adds a Detector to the set Parse consults. This lets
+// callers recognise locale-specific quote headers (French "Le ... a
+// écrit :", German "Am ... schrieb ...", Spanish "El ... escribió:") or
+// custom signature markers without forking the package. RegisterDetector
+// is typically called from an init function; it is not safe to call
+// concurrently with Parse.
+func RegisterDetector(d Detector) {
+	registry = append(registry, d)
+}
+
+func init() {
+	RegisterDetector(DetectorFunc(detectQuoteHeader))
+	RegisterDetector(DetectorFunc(detectGmailAltQuoteHeader))
+	RegisterDetector(DetectorFunc(detectYahooOriginalMessage))
+	RegisterDetector(DetectorFunc(detectOutlookHeader))
+	RegisterDetector(DetectorFunc(detectForwardedMessage))
+	RegisterDetector(DetectorFunc(detectQuotedLine))
+	RegisterDetector(DetectorFunc(detectSignature))
+}
+
+var (
+	// quoteHeaderRegexp matches the first line of an "On <date>, <person>
+	// wrote:" block. The header may span more than one line (e.g. when the
+	// sender's name wraps), so this only recognises where such a block
+	// *starts*; quoteHeaderEndRegexp is used to find where it ends.
+	quoteHeaderRegexp    = regexp.MustCompile(`^On\s`)
+	quoteHeaderEndRegexp = regexp.MustCompile(`wrote:\s*$`)
+
+	// quoteHeaderDateRegexp requires the candidate block to also contain a
+	// date-ish token, e.g. "Jan 5" or "Oct 1, 2012", so that ordinary prose
+	// starting with "On" - "On your remote host, the logs ... wrote: that
+	// note" - isn't mistaken for a header just because some later line
+	// happens to end in "wrote:".
+	quoteHeaderDateRegexp = regexp.MustCompile(`(?i)\b(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2}\b`)
+
+	// gmailAltQuoteHeaderRegexp matches Gmail's alternate quote header,
+	// used when the reply doesn't go through the usual "On ... wrote:"
+	// phrasing, e.g. "2016-01-05 12:00 GMT+00:00 Bob <bob@example.com>:".
+	gmailAltQuoteHeaderRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\s.*:\s*$`)
+
+	// yahooOriginalMessageRegexp matches Yahoo Mail's reply separator.
+	yahooOriginalMessageRegexp = regexp.MustCompile(`^-{3,}\s*Original Message\s*-{3,}\s*$`)
+
+	// outlookHeaderRegexp matches the start of Outlook's plain-text
+	// From:/Sent:/To:/Subject: reply or forward header block.
+	outlookHeaderRegexp = regexp.MustCompile(`^From:\s`)
+
+	// forwardedMessageRegexp matches the banner most mail clients insert
+	// above a forwarded message.
+	forwardedMessageRegexp = regexp.MustCompile(`^-{3,}\s*Forwarded message\s*-{3,}\s*$`)
+
+	// quotedLineRegexp matches a line of quoted text, e.g. "> like this".
+	quotedLineRegexp = regexp.MustCompile(`^>`)
+
+	// signatureRegexp matches common signature delimiters and markers:
+	// "--"/"__" on their own line, a name introduced with a bare dash or
+	// underscore, or a "Sent from my <device>" mobile signature.
+	signatureRegexp = regexp.MustCompile(`^(--|__)\s*$|^[-_]\w|^Sent from my (\w+\s*){1,3}$`)
+)
+
+// detectQuoteHeader recognises an "On <date>, <person> wrote:" header, which
+// may span up to 4 lines before the line ending in "wrote:" is found. Both a
+// date-ish token and that trailing "wrote:" are required, so a paragraph
+// that merely starts with "On" and happens to contain the word "wrote:"
+// later on isn't mistaken for one.
+func detectQuoteHeader(lines []string) (FragmentKind, int) {
+	if !quoteHeaderRegexp.MatchString(lines[0]) {
+		return 0, 0
+	}
+	for i := 0; i < len(lines) && i < 4; i++ {
+		if !quoteHeaderEndRegexp.MatchString(lines[i]) {
+			continue
+		}
+		candidate := strings.Join(lines[:i+1], " ")
+		if !quoteHeaderDateRegexp.MatchString(candidate) {
+			return 0, 0
+		}
+		return FragmentKindQuoteHeader, i + 1
+	}
+	return 0, 0
+}
+
+func detectGmailAltQuoteHeader(lines []string) (FragmentKind, int) {
+	if gmailAltQuoteHeaderRegexp.MatchString(lines[0]) {
+		return FragmentKindQuoteBlock, 1
+	}
+	return 0, 0
+}
+
+func detectYahooOriginalMessage(lines []string) (FragmentKind, int) {
+	if yahooOriginalMessageRegexp.MatchString(lines[0]) {
+		return FragmentKindQuoteBlock, 1
+	}
+	return 0, 0
+}
+
+func detectOutlookHeader(lines []string) (FragmentKind, int) {
+	if outlookHeaderRegexp.MatchString(lines[0]) {
+		return FragmentKindQuoteBlock, 1
+	}
+	return 0, 0
+}
+
+func detectForwardedMessage(lines []string) (FragmentKind, int) {
+	if forwardedMessageRegexp.MatchString(lines[0]) {
+		return FragmentKindForwarded, 1
+	}
+	return 0, 0
+}
+
+func detectQuotedLine(lines []string) (FragmentKind, int) {
+	if quotedLineRegexp.MatchString(lines[0]) {
+		return FragmentKindQuoted, 1
+	}
+	return 0, 0
+}
+
+func detectSignature(lines []string) (FragmentKind, int) {
+	if signatureRegexp.MatchString(lines[0]) {
+		return FragmentKindSignature, 1
+	}
+	return 0, 0
+}
+
+// match runs the registered detectors, in order, against a block's lines
+// and returns the first match.
+func match(lines []string) (kind FragmentKind, consumed int, ok bool) {
+	for _, d := range registry {
+		if kind, consumed = d.Match(lines); consumed > 0 {
+			return kind, consumed, true
+		}
+	}
+	return 0, 0, false
+}
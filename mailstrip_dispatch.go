@@ -0,0 +1,99 @@
+package mailstrip
+
+// blockSource supplies the blocks that follow the one currently being
+// dispatched, for the two FragmentKinds that consume the rest of the
+// message (QuoteBlock, Forwarded). Parse and Parser.step each have their
+// own way of producing blocks (a pre-split slice, or a blockReader reading
+// further from the underlying io.Reader), so they each supply their own
+// blockSource to the shared dispatch below.
+type blockSource interface {
+	next() (block, bool)
+}
+
+// sliceBlockSource walks a []block Parse has already split in full.
+type sliceBlockSource struct {
+	blocks []block
+	i      int
+}
+
+func (s *sliceBlockSource) next() (block, bool) {
+	if s.i >= len(s.blocks) {
+		return block{}, false
+	}
+	b := s.blocks[s.i]
+	s.i++
+	return b, true
+}
+
+// appendGap appends blanks blank lines to f, so a gap between two blocks
+// that end up in the same Fragment isn't lost.
+func appendGap(f *Fragment, blanks int) {
+	for i := 0; i < blanks; i++ {
+		f.lines = append(f.lines, "")
+	}
+}
+
+// buildFragment builds the Fragment for a block match already found by
+// match(b.lines) (kind, consumed, true), given the hidden state carried in
+// from the preceding block. FragmentKindQuoteBlock and FragmentKindForwarded
+// consume the rest of the message, so this pulls the remaining blocks from
+// src itself rather than leaving that to the caller. Parse and Parser.step
+// both call this for their per-block dispatch, so a fix to one can't
+// silently miss the other.
+//
+// It returns the built Fragment, the hidden state to carry forward to the
+// next block, and whether the message is now fully consumed.
+func buildFragment(kind FragmentKind, b block, consumed int, hidden bool, src blockSource) (f *Fragment, nextHidden bool, done bool) {
+	switch kind {
+	case FragmentKindQuoteHeader:
+		// The header's own line(s) are visible on their own - email_1_2's
+		// "On ... wrote:" is followed by a separate, visible reply. But
+		// when the same block also carries quoted continuation lines (a
+		// locale's quote header sharing a block with its quoted text, as
+		// in email_french_quoteheader), the whole block is quoted history
+		// and must be hidden.
+		h := hidden || consumed < len(b.lines)
+		f = &Fragment{quoted: true, hidden: h, quoteHeader: parseOnWroteHeader(b.lines[:consumed])}
+		f.lines = append(f.lines, b.lines...)
+		return f, h, false
+
+	case FragmentKindQuoteBlock:
+		f = &Fragment{quoted: true, hidden: true}
+		f.lines = append(f.lines, b.lines...)
+		for {
+			rest, ok := src.next()
+			if !ok {
+				break
+			}
+			appendGap(f, rest.leadingBlanks)
+			f.lines = append(f.lines, rest.lines...)
+		}
+		f.quoteHeader = parseFieldHeader(f.lines)
+		return f, true, true
+
+	case FragmentKindForwarded:
+		f = &Fragment{forwarded: true}
+		f.lines = append(f.lines, b.lines...)
+		for {
+			rest, ok := src.next()
+			if !ok {
+				break
+			}
+			appendGap(f, rest.leadingBlanks)
+			f.lines = append(f.lines, rest.lines...)
+		}
+		f.quoteHeader = parseFieldHeader(f.lines)
+		return f, hidden, true
+
+	case FragmentKindQuoted:
+		f = &Fragment{quoted: true, hidden: true}
+		f.lines = append(f.lines, b.lines...)
+		return f, true, false
+
+	case FragmentKindSignature:
+		f = &Fragment{signature: true, hidden: true}
+		f.lines = append(f.lines, b.lines...)
+		return f, true, false
+	}
+	return nil, hidden, false
+}
@@ -0,0 +1,135 @@
+package mailstrip
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ParseHTML splits an HTML-formatted email body into Fragments, walking the
+// DOM instead of scanning lines the way Parse does. It recognises the
+// quote/forward wrappers used by common webmail clients: a standard or
+// Apple Mail `<blockquote type="cite">`, Gmail's `<div class="gmail_quote">`,
+// and Outlook's `<div id="divRplyFwdMsg">`. Content inside
+// `<div class="gmail_signature">` is treated as a trailing signature.
+//
+// Fragments returned by ParseHTML carry their original markup, available
+// via Fragment.HTML, in addition to the flattened text returned by
+// Fragment.String.
+func ParseHTML(body string) Email {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return Email{&Fragment{lines: []string{body}, html: body}}
+	}
+
+	var email Email
+	walkHTML(doc, htmlState{}, &email)
+	return email
+}
+
+// htmlState tracks which quote/signature wrapper, if any, the DOM walk is
+// currently inside.
+type htmlState struct {
+	quoted    bool
+	hidden    bool
+	signature bool
+}
+
+// quoteWrapperState reports the Fragment state a node's subtree should be
+// parsed with if the node is one of the recognised quote/forward/signature
+// wrappers.
+func quoteWrapperState(n *html.Node) (htmlState, bool) {
+	if n.Type != html.ElementNode {
+		return htmlState{}, false
+	}
+	switch {
+	case n.DataAtom == atom.Blockquote && htmlAttr(n, "type") == "cite":
+		return htmlState{quoted: true, hidden: true}, true
+	case n.DataAtom == atom.Div && hasHTMLClass(n, "gmail_quote"):
+		return htmlState{quoted: true, hidden: true}, true
+	case n.DataAtom == atom.Div && htmlAttr(n, "id") == "divRplyFwdMsg":
+		return htmlState{quoted: true, hidden: true}, true
+	case n.DataAtom == atom.Div && hasHTMLClass(n, "gmail_signature"):
+		return htmlState{signature: true, hidden: true}, true
+	}
+	return htmlState{}, false
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasHTMLClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// walkHTML walks the DOM depth-first. Any subtree rooted at a recognised
+// quote/forward/signature wrapper is flattened into a single Fragment;
+// everything else is emitted as one Fragment per non-blank text node.
+func walkHTML(n *html.Node, state htmlState, email *Email) {
+	if wrapped, ok := quoteWrapperState(n); ok {
+		emitHTMLFragment(n, wrapped, email)
+		return
+	}
+
+	switch n.Type {
+	case html.DocumentNode, html.ElementNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walkHTML(c, state, email)
+		}
+	case html.TextNode:
+		text := strings.TrimSpace(n.Data)
+		if text == "" {
+			return
+		}
+		*email = append(*email, &Fragment{
+			quoted:    state.quoted,
+			signature: state.signature,
+			hidden:    state.hidden,
+			lines:     []string{text},
+			html:      n.Data,
+		})
+	}
+}
+
+// emitHTMLFragment renders n's subtree as the markup for a single Fragment,
+// with its flattened, whitespace-trimmed text as the fragment's lines.
+func emitHTMLFragment(n *html.Node, state htmlState, email *Email) {
+	var markup bytes.Buffer
+	html.Render(&markup, n)
+
+	*email = append(*email, &Fragment{
+		quoted:    state.quoted,
+		signature: state.signature,
+		hidden:    state.hidden,
+		lines:     []string{strings.TrimSpace(htmlTextContent(n))},
+		html:      markup.String(),
+	})
+}
+
+func htmlTextContent(n *html.Node) string {
+	var text bytes.Buffer
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return text.String()
+}
@@ -0,0 +1,157 @@
+package mailstrip
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// blockReader incrementally groups the lines read from an io.Reader into
+// blocks, the same unit Parse works with, without holding the whole body
+// in memory.
+type blockReader struct {
+	scanner       *bufio.Scanner
+	pendingBlanks int
+	eof           bool
+}
+
+func newBlockReader(r io.Reader) *blockReader {
+	return &blockReader{scanner: bufio.NewScanner(r)}
+}
+
+// next returns the next block, or ok == false once the reader is
+// exhausted.
+func (br *blockReader) next() (b block, ok bool) {
+	if br.eof {
+		return block{}, false
+	}
+
+	b.leadingBlanks = br.pendingBlanks
+	br.pendingBlanks = 0
+
+	started := false
+	for br.scanner.Scan() {
+		line := br.scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if started {
+				br.pendingBlanks = 1
+				return b, true
+			}
+			b.leadingBlanks++
+			continue
+		}
+		started = true
+		b.lines = append(b.lines, line)
+	}
+	br.eof = true
+
+	return b, started
+}
+
+// Parser streams Fragments out of a plain-text email body. Unlike Parse,
+// which buffers the whole body up front, a Parser only buffers up to the
+// next Fragment boundary, so a long visible reply sitting above a quoted
+// history doesn't need the whole thread in memory just to read the part a
+// caller cares about.
+//
+// That saving doesn't apply once the quoted history itself is reached: a
+// Yahoo/Outlook-style quote separator or a forwarded message banner
+// produces a single Fragment out of everything from there to the end of
+// the body, which Next still has to buffer in full before it can return
+// it - the same as Parse would. A Parser only bounds memory for the
+// visible part of a message; it doesn't bound the size of its trailing
+// quoted/forwarded block.
+type Parser struct {
+	br     *blockReader
+	hidden bool
+	cur    *Fragment
+	queue  []*Fragment
+	done   bool
+	// held is a quote separator or forwarded banner block whose preceding
+	// Fragment was just queued; it's built on the next step, so Next can
+	// return that Fragment before the rest of the body is read.
+	held *block
+}
+
+// NewParser returns a Parser that reads a plain-text email body from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{br: newBlockReader(r)}
+}
+
+// Next returns the next Fragment in the body. It returns io.EOF once the
+// body is exhausted.
+func (p *Parser) Next() (*Fragment, error) {
+	for len(p.queue) == 0 && !p.done {
+		p.step()
+	}
+	if len(p.queue) == 0 {
+		return nil, io.EOF
+	}
+	f := p.queue[0]
+	p.queue = p.queue[1:]
+	return f, nil
+}
+
+func (p *Parser) flush() {
+	if p.cur != nil {
+		p.queue = append(p.queue, p.cur)
+		p.cur = nil
+	}
+}
+
+func (p *Parser) flushWithGap(blanks int) {
+	if p.cur != nil {
+		appendGap(p.cur, blanks)
+	}
+	p.flush()
+}
+
+// step reads and processes one more block from the body, enqueueing any
+// Fragment(s) it completes. It sets p.done once the body is exhausted and
+// there's nothing left to flush.
+func (p *Parser) step() {
+	var b block
+	if p.held != nil {
+		b, p.held = *p.held, nil
+	} else {
+		var ok bool
+		if b, ok = p.br.next(); !ok {
+			p.flush()
+			p.done = true
+			return
+		}
+	}
+
+	kind, consumed, matched := match(b.lines)
+	if !matched {
+		h := p.hidden
+		if p.cur != nil && !p.cur.quoted && !p.cur.signature && !p.cur.forwarded && !h {
+			appendGap(p.cur, b.leadingBlanks)
+			p.cur.lines = append(p.cur.lines, b.lines...)
+			return
+		}
+		p.flush()
+		p.cur = &Fragment{hidden: h}
+		p.cur.lines = append(p.cur.lines, b.lines...)
+		return
+	}
+
+	if kind == FragmentKindQuoteHeader {
+		// See the matching case in Parse: the blank line before a quote
+		// header is the separator, not trailing whitespace that belongs to
+		// the preceding fragment, so it's dropped rather than appended.
+		p.flush()
+	} else {
+		p.flushWithGap(b.leadingBlanks)
+	}
+
+	if len(p.queue) > 0 && (kind == FragmentKindQuoteBlock || kind == FragmentKindForwarded) {
+		p.held = &b
+		return
+	}
+
+	f, hidden, done := buildFragment(kind, b, consumed, p.hidden, p.br)
+	p.queue = append(p.queue, f)
+	p.hidden = hidden
+	p.done = done
+}
@@ -0,0 +1,133 @@
+package mailstrip
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// QuoteHeader holds the structured fields recognised from a reply/forward
+// boundary: an "On <date>, <person> wrote:" line, or an Outlook-style
+// From:/Sent:/To:/Subject: block. It is nil for fragments that aren't
+// recognised as one of these.
+type QuoteHeader struct {
+	Date    time.Time
+	From    string
+	To      []string
+	Cc      []string
+	Subject string
+}
+
+// QuoteHeader returns the structured fields of this fragment's reply/forward
+// boundary, or nil if the fragment isn't one (or its header couldn't be
+// parsed into fields).
+func (f *Fragment) QuoteHeader() *QuoteHeader { return f.quoteHeader }
+
+// onWroteRegexp splits a flattened "On <date>, <person> wrote:" line into
+// its date and person parts. The first group is greedy so that, when the
+// date itself contains a comma (as in "Jan 5, 2016, at 3:00 PM"), it backs
+// off to the last comma in the line - which is the one separating the date
+// from the person, since the person part never contains one.
+var onWroteRegexp = regexp.MustCompile(`(?i)^On\s+(.+),\s+([^,]+?)\s+wrote:\s*$`)
+
+// dateLayouts are the date formats commonly seen in "On ... wrote:" headers
+// and Outlook's Sent:/Date: fields, tried in order.
+var dateLayouts = []string{
+	"Jan 2, 2006, at 3:04 PM",
+	"Jan 2, 2006 at 3:04 PM",
+	"January 2, 2006 at 3:04 PM",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"Monday, January 2, 2006 3:04 PM",
+	"1/2/2006 3:04 PM",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func parseQuoteHeaderDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parseAddress(s string) string {
+	if addr, err := mail.ParseAddress(s); err == nil {
+		return addr.Address
+	}
+	return strings.TrimSpace(s)
+}
+
+func parseAddressList(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	addrs, err := mail.ParseAddressList(s)
+	if err != nil {
+		return []string{s}
+	}
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = a.Address
+	}
+	return out
+}
+
+// parseOnWroteHeader parses the lines making up a recognised "On <date>,
+// <person> wrote:" header (which may span a few lines; see
+// detectQuoteHeader) into a QuoteHeader.
+func parseOnWroteHeader(lines []string) *QuoteHeader {
+	text := strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	m := onWroteRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	return &QuoteHeader{
+		Date: parseQuoteHeaderDate(m[1]),
+		From: parseAddress(m[2]),
+	}
+}
+
+var (
+	fromFieldRegexp    = regexp.MustCompile(`(?i)^From:\s*(.*)$`)
+	sentFieldRegexp    = regexp.MustCompile(`(?i)^(?:Sent|Date):\s*(.*)$`)
+	toFieldRegexp      = regexp.MustCompile(`(?i)^To:\s*(.*)$`)
+	ccFieldRegexp      = regexp.MustCompile(`(?i)^Cc:\s*(.*)$`)
+	subjectFieldRegexp = regexp.MustCompile(`(?i)^Subject:\s*(.*)$`)
+)
+
+// parseFieldHeader parses a From:/Sent:/To:/Cc:/Subject: block, as used by
+// Outlook's and Yahoo's reply/forward headers, into a QuoteHeader. It
+// returns nil if none of those fields are present.
+func parseFieldHeader(lines []string) *QuoteHeader {
+	qh := &QuoteHeader{}
+	found := false
+	for _, line := range lines {
+		switch {
+		case fromFieldRegexp.MatchString(line):
+			found = true
+			qh.From = parseAddress(fromFieldRegexp.FindStringSubmatch(line)[1])
+		case sentFieldRegexp.MatchString(line):
+			found = true
+			qh.Date = parseQuoteHeaderDate(sentFieldRegexp.FindStringSubmatch(line)[1])
+		case toFieldRegexp.MatchString(line):
+			found = true
+			qh.To = parseAddressList(toFieldRegexp.FindStringSubmatch(line)[1])
+		case ccFieldRegexp.MatchString(line):
+			found = true
+			qh.Cc = parseAddressList(ccFieldRegexp.FindStringSubmatch(line)[1])
+		case subjectFieldRegexp.MatchString(line):
+			found = true
+			qh.Subject = strings.TrimSpace(subjectFieldRegexp.FindStringSubmatch(line)[1])
+		}
+	}
+	if !found {
+		return nil
+	}
+	return qh
+}
@@ -2,10 +2,12 @@ package mailstrip
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -180,6 +182,81 @@ I am currently using the Java HTTP API.
 			&emailStringChecker{equalsString("Fine, and you?")},
 		},
 	},
+	{
+		"a paragraph starting with On is not mistaken for a quote header",
+		"greedy_on",
+		[]checker{fragmentCountChecker(1)},
+	},
+	{
+		"a 2nd paragraph starting with On is still recognised as body text",
+		"email_2nd_paragraph_starting_with_on",
+		[]checker{
+			&attributeChecker{"Quoted", []bool{false, true}},
+			&fragmentStringChecker{0, regexp.MustCompile("(?m)^On your remote host")},
+			&fragmentStringChecker{1, regexp.MustCompile("(?m)^On Jan 1, 2016")},
+		},
+	},
+	{
+		"reads a multi-line signature as one fragment",
+		"email_1_8",
+		[]checker{
+			&attributeChecker{"Signature", []bool{false, true}},
+			&attributeChecker{"Hidden", []bool{false, true}},
+			&fragmentStringChecker{1, regexp.MustCompile("(?m)^--\nKind regards,\nJohn Appleseed\nAcme Inc.$")},
+		},
+	},
+	{
+		"deals with windows line endings and a mobile signature",
+		"email_1_9",
+		[]checker{&emailStringChecker{equalsString("Thanks a bunch!")}},
+	},
+	{
+		"Outlook's Date: field is recognised alongside Sent:",
+		"email_2_2",
+		[]checker{&emailStringChecker{equalsString("On my way.")}},
+	},
+	{
+		"an Outlook reply header with several To: addresses and a Cc: is stripped",
+		"email_2_3",
+		[]checker{&emailStringChecker{equalsString("Sounds good.")}},
+	},
+	{
+		"Outlook's reply header is recognised with only a From: and Subject:",
+		"email_2_4",
+		[]checker{&emailStringChecker{equalsString("Talk soon.")}},
+	},
+	{
+		"an Outlook reply header with extra spacing and odd-cased Sent:/To:/Subject: fields is stripped",
+		"email_2_5",
+		[]checker{&emailStringChecker{equalsString("Agreed.")}},
+	},
+	{
+		"a Yahoo separator followed by an Outlook-style field block is still one quote block",
+		"email_2_6",
+		[]checker{
+			fragmentCountChecker(2),
+			&emailStringChecker{equalsString("Works for me.")},
+		},
+	},
+	{
+		"gmail's alternate quote header is recognised with a GMT offset",
+		"email_2_7",
+		[]checker{&emailStringChecker{equalsString("Sure thing.")}},
+	},
+	{
+		"a forwarded message with an Outlook-style header stays visible",
+		"email_2_8",
+		[]checker{
+			&attributeChecker{"Forwarded", []bool{false, true}},
+			&attributeChecker{"Hidden", []bool{false, false}},
+			&emailStringChecker{regexp.MustCompile("(?s)See below.*Original content here.")},
+		},
+	},
+	{
+		"an Outlook reply header with more than two Cc: addresses is stripped",
+		"email_2_9",
+		[]checker{&emailStringChecker{equalsString("Noted, thanks.")}},
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -210,6 +287,152 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseHTML(t *testing.T) {
+	text, err := loadHTMLFixture("email_gmail_quote")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	parsed := ParseHTML(text)
+
+	if err := (&attributeChecker{"Quoted", []bool{false, true}}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if err := (&attributeChecker{"Hidden", []bool{false, true}}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if err := (&emailStringChecker{equalsString("Sounds good, see you there.")}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(parsed[1].HTML(), "gmail_quote") {
+		t.Errorf("Fragment.HTML() lost the original gmail_quote markup: %q", parsed[1].HTML())
+	}
+}
+
+func TestRegisterDetector(t *testing.T) {
+	frenchQuoteHeader := regexp.MustCompile(`^Le .+ a écrit\s*:\s*$`)
+	RegisterDetector(DetectorFunc(func(lines []string) (FragmentKind, int) {
+		if frenchQuoteHeader.MatchString(lines[0]) {
+			return FragmentKindQuoteHeader, 1
+		}
+		return 0, 0
+	}))
+
+	text, err := loadFixture("email_french_quoteheader")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	parsed := Parse(text)
+
+	if err := (&attributeChecker{"Quoted", []bool{false, true}}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if err := (&fragmentStringChecker{0, equalsString("Bonjour,")}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if err := (&fragmentStringChecker{1, regexp.MustCompile("^Le ")}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+	if err := (&attributeChecker{"Hidden", []bool{false, true}}).Check(parsed); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuoteHeader(t *testing.T) {
+	text, err := loadFixture("email_2_1")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	parsed := Parse(text)
+	qh := parsed[1].QuoteHeader()
+	if qh == nil {
+		t.Fatal("expected a QuoteHeader for the Outlook reply block")
+	}
+	if qh.From != "bob@example.com" {
+		t.Errorf("From = %q, want %q", qh.From, "bob@example.com")
+	}
+	if want := []string{"Alice Smith"}; len(qh.To) != 1 || qh.To[0] != want[0] {
+		t.Errorf("To = %v, want %v", qh.To, want)
+	}
+	if qh.Subject != "RE: Question" {
+		t.Errorf("Subject = %q, want %q", qh.Subject, "RE: Question")
+	}
+	if qh.Date.IsZero() {
+		t.Error("Date was not parsed")
+	}
+}
+
+func TestQuoteHeaderFromAddress(t *testing.T) {
+	text, err := loadFixture("email_from_address_in_quote_header")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	qh := Parse(text)[1].QuoteHeader()
+	if qh == nil {
+		t.Fatal("expected a QuoteHeader for the On ... wrote: fragment")
+	}
+	if qh.From != "bob@example.com" {
+		t.Errorf("From = %q, want %q", qh.From, "bob@example.com")
+	}
+}
+
+func TestQuoteHeaderMultipleRecipients(t *testing.T) {
+	text, err := loadFixture("email_2_3")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	qh := Parse(text)[1].QuoteHeader()
+	if qh == nil {
+		t.Fatal("expected a QuoteHeader for the Outlook reply block")
+	}
+	wantTo := []string{"frank@example.com", "grace@example.com"}
+	if len(qh.To) != len(wantTo) || qh.To[0] != wantTo[0] || qh.To[1] != wantTo[1] {
+		t.Errorf("To = %v, want %v", qh.To, wantTo)
+	}
+	wantCc := []string{"heidi@example.com"}
+	if len(qh.Cc) != 1 || qh.Cc[0] != wantCc[0] {
+		t.Errorf("Cc = %v, want %v", qh.Cc, wantCc)
+	}
+}
+
+func TestParser(t *testing.T) {
+	text, err := loadFixture("email_1_2")
+	if err != nil {
+		t.Fatalf("could not load fixture: %s", err)
+	}
+
+	want := Parse(text)
+
+	p := NewParser(strings.NewReader(text))
+	var got Email
+	for {
+		f, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %s", err)
+		}
+		got = append(got, f)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d fragments, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].String() != want[i].String() {
+			t.Errorf("fragment %d: got %q, want %q", i, got[i].String(), want[i].String())
+		}
+		if got[i].Quoted() != want[i].Quoted() || got[i].Hidden() != want[i].Hidden() || got[i].Signature() != want[i].Signature() {
+			t.Errorf("fragment %d: attributes differ from Parse's result", i)
+		}
+	}
+}
+
 type checker interface {
 	Check(email Email) error
 }
@@ -307,3 +530,9 @@ func loadFixture(name string) (string, error) {
 	data, err := ioutil.ReadFile(fixturePath)
 	return string(data), err
 }
+
+func loadHTMLFixture(name string) (string, error) {
+	fixturePath := filepath.Join(fixturesDir, name+".html")
+	data, err := ioutil.ReadFile(fixturePath)
+	return string(data), err
+}